@@ -0,0 +1,90 @@
+package whatsgate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Sentinel errors callers can match against with errors.Is, regardless of
+// the exact status code or API error code WhatsGate used for a given
+// failure.
+var (
+	ErrInvalidNumber   = errors.New("whatsgate: invalid WhatsApp number")
+	ErrUnauthorized    = errors.New("whatsgate: unauthorized")
+	ErrRateLimited     = errors.New("whatsgate: rate limited")
+	ErrMediaTooLarge   = errors.New("whatsgate: media too large")
+	ErrNotWhatsAppUser = errors.New("whatsgate: recipient is not a WhatsApp user")
+)
+
+// APIError is returned for any non-2xx response from the WhatsGate API.
+// Use errors.As to recover it and errors.Is to test it against the
+// sentinel errors above.
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	RequestID  string
+	Body       []byte
+}
+
+func (e *APIError) Error() string {
+	if e.Message != "" {
+		return fmt.Sprintf("whatsgate: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("whatsgate: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+}
+
+// Retryable reports whether the request that produced e is safe to retry,
+// i.e. it was throttled or the server failed transiently.
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// Is matches e against the sentinel errors in this package so that
+// errors.Is(err, ErrRateLimited) and friends work without callers having to
+// inspect StatusCode or Code themselves. Matching prefers the API's own Code
+// string; a StatusCode fallback is only used where the HTTP status is
+// unambiguous for that sentinel (429 and 413 aren't used for anything else by
+// this API). Statuses like 400 and 401/403 are reused for multiple kinds of
+// failure, so those sentinels match on Code alone.
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrInvalidNumber:
+		return e.Code == "invalid_number"
+	case ErrUnauthorized:
+		return e.Code == "unauthorized"
+	case ErrRateLimited:
+		return e.Code == "rate_limited" || e.StatusCode == http.StatusTooManyRequests
+	case ErrMediaTooLarge:
+		return e.Code == "media_too_large" || e.StatusCode == http.StatusRequestEntityTooLarge
+	case ErrNotWhatsAppUser:
+		return e.Code == "not_whatsapp_user"
+	default:
+		return false
+	}
+}
+
+type apiErrorEnvelope struct {
+	Error struct {
+		Code      string `json:"code"`
+		Message   string `json:"message"`
+		RequestID string `json:"request_id"`
+	} `json:"error"`
+}
+
+// parseAPIError builds an *APIError from a non-2xx response, decoding the
+// API's JSON error envelope when the body has one.
+func parseAPIError(status int, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: status, Body: body}
+
+	var env apiErrorEnvelope
+	if err := json.Unmarshal(body, &env); err == nil {
+		apiErr.Code = env.Error.Code
+		apiErr.Message = env.Error.Message
+		apiErr.RequestID = env.Error.RequestID
+	}
+
+	return apiErr
+}
@@ -0,0 +1,217 @@
+package whatsgate
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// EventKind discriminates the payloads FetchEvents and WebhookHandler can
+// deliver.
+type EventKind string
+
+const (
+	EventMessage EventKind = "message"
+	EventAck     EventKind = "ack"
+	EventStatus  EventKind = "status"
+)
+
+// Event is an incoming message, delivery acknowledgement, or status update
+// pushed by WhatsGate, either polled via FetchEvents or delivered to a
+// WebhookHandler.
+type Event struct {
+	Kind      EventKind `json:"kind"`
+	ID        string    `json:"id"`
+	From      string    `json:"from"`
+	FromName  string    `json:"from_name"`
+	Body      string    `json:"body"`
+	Type      string    `json:"type"`
+	Timestamp int       `json:"timestamp"`
+	HasMedia  bool      `json:"hasMedia"`
+	MediaKey  string    `json:"mediaKey"`
+	Ack       int       `json:"ack,omitempty"`
+	Status    string    `json:"status,omitempty"`
+}
+
+// Handler reacts to events fanned out by a Dispatcher.
+type Handler interface {
+	OnMessage(Event)
+	OnAck(Event)
+	OnStatus(Event)
+}
+
+// Dispatcher routes events to a Handler based on their Kind.
+type Dispatcher struct {
+	handler Handler
+}
+
+// NewDispatcher returns a Dispatcher that fans events out to handler.
+func NewDispatcher(handler Handler) *Dispatcher {
+	return &Dispatcher{handler: handler}
+}
+
+// Dispatch delivers a single event to the underlying Handler.
+func (d *Dispatcher) Dispatch(e Event) {
+	switch e.Kind {
+	case EventMessage:
+		d.handler.OnMessage(e)
+	case EventAck:
+		d.handler.OnAck(e)
+	case EventStatus:
+		d.handler.OnStatus(e)
+	}
+}
+
+// Run dispatches events from events until it is closed or ctx is done.
+func (d *Dispatcher) Run(ctx context.Context, events <-chan Event) {
+	for {
+		select {
+		case e, ok := <-events:
+			if !ok {
+				return
+			}
+			d.Dispatch(e)
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// FetchEvents long-polls the /events endpoint for events newer than
+// lastEventID, or from the beginning of the backlog if lastEventID is empty.
+func (c *Client) FetchEvents(ctx context.Context, lastEventID string) ([]Event, error) {
+	path := "/events"
+	if lastEventID != "" {
+		path += "?since=" + url.QueryEscape(lastEventID)
+	}
+
+	respBody, status, err := c.do(ctx, http.MethodGet, path, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, parseAPIError(status, respBody)
+	}
+
+	var events []Event
+	if err := json.Unmarshal(respBody, &events); err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// pollIdleBackoff is the delay Poll waits before re-issuing FetchEvents
+// after an empty result, guarding against a hot loop if /events ever
+// returns promptly instead of blocking until events are available.
+const pollIdleBackoff = time.Second
+
+// Poll repeatedly calls FetchEvents and streams the results on the returned
+// channel, which is closed once ctx is done. Transient fetch errors are
+// logged and retried rather than closing the channel.
+func (c *Client) Poll(ctx context.Context) <-chan Event {
+	out := make(chan Event)
+
+	go func() {
+		defer close(out)
+
+		lastEventID := ""
+		for {
+			events, err := c.FetchEvents(ctx, lastEventID)
+			if err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				c.logger.Error("whatsgate: poll failed", "error", err)
+				if sleepErr := c.sleep(ctx, time.Second); sleepErr != nil {
+					return
+				}
+				continue
+			}
+
+			if len(events) == 0 {
+				if sleepErr := c.sleep(ctx, pollIdleBackoff); sleepErr != nil {
+					return
+				}
+				continue
+			}
+
+			for _, e := range events {
+				select {
+				case out <- e:
+					lastEventID = e.ID
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return out
+}
+
+// Events returns the channel webhook events delivered via WebhookHandler
+// are forwarded to. Callers must drain it to avoid the handler dropping events.
+func (c *Client) Events() <-chan Event {
+	return c.webhooks
+}
+
+// WebhookHandler returns an http.Handler that accepts incoming events
+// pushed by WhatsGate, validates the request against secret, and forwards
+// decoded events onto the channel returned by Client.Events. Requests are
+// authenticated either via a matching X-Api-Key header or, if present, an
+// X-Signature header carrying the hex-encoded HMAC-SHA256 of the raw body
+// keyed with secret.
+func (c *Client) WebhookHandler(secret string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !validWebhookRequest(r, body, secret) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var event Event
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+
+		select {
+		case c.webhooks <- event:
+		default:
+			c.logger.Warn("whatsgate: webhook event dropped, Events() channel is full")
+		}
+
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func validWebhookRequest(r *http.Request, body []byte, secret string) bool {
+	if apiKey := r.Header.Get("X-Api-Key"); apiKey != "" {
+		return hmac.Equal([]byte(apiKey), []byte(secret))
+	}
+
+	sig := r.Header.Get("X-Signature")
+	if sig == "" {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+
+	return hmac.Equal([]byte(sig), []byte(expected))
+}
@@ -0,0 +1,220 @@
+package whatsgate
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+
+	"github.com/bobTheBuilder7/WhatsgateClient/mediacrypto"
+)
+
+// MediaKind identifies the kind of attachment a MediaUpload carries.
+type MediaKind string
+
+const (
+	MediaImage    MediaKind = "image"
+	MediaVideo    MediaKind = "video"
+	MediaAudio    MediaKind = "audio"
+	MediaDocument MediaKind = "document"
+	MediaSticker  MediaKind = "sticker"
+)
+
+// streamingThreshold is the payload size above which SendMedia switches from
+// buffering + base64 to a streamed multipart/form-data upload.
+const streamingThreshold = 4 << 20 // 4 MiB
+
+// MediaUpload describes an attachment to send via SendMedia. Data is read
+// from Reader, which is buffered in memory for payloads up to the streaming
+// threshold and streamed otherwise.
+type MediaUpload struct {
+	Reader   io.Reader
+	Mimetype string
+	Filename string
+	Caption  string
+	Kind     MediaKind
+}
+
+// mediaAPIType maps a MediaKind to the type string the /send and
+// /send/media endpoints expect. Documents use "doc" there, matching
+// DocumentMessage.marshalMessage, even though the MediaKind constant reads
+// "document".
+func mediaAPIType(kind MediaKind) string {
+	if kind == MediaDocument {
+		return "doc"
+	}
+	return string(kind)
+}
+
+// SendMedia sends m to recipient. Small payloads are base64-encoded and sent
+// the same way SendPDF does; payloads over the streaming threshold are sent
+// as multipart/form-data so the whole file is never buffered in memory.
+func (c *Client) SendMedia(ctx context.Context, recipient string, m MediaUpload) (MessageResponse, error) {
+	var head bytes.Buffer
+	n, err := io.CopyN(&head, m.Reader, streamingThreshold+1)
+	if err != nil && err != io.EOF {
+		return MessageResponse{}, err
+	}
+
+	if n <= streamingThreshold {
+		return c.sendMediaBuffered(ctx, recipient, m, head.Bytes())
+	}
+
+	rest := io.MultiReader(&head, m.Reader)
+	return c.sendMediaMultipart(ctx, recipient, m, rest)
+}
+
+func (c *Client) sendMediaBuffered(ctx context.Context, recipient string, m MediaUpload, data []byte) (MessageResponse, error) {
+	body, err := json.Marshal(MessagePDFRequest{
+		WhatsappID: c.WhatsappID,
+		Async:      false,
+		Recipient:  Recipient{Number: recipient},
+		Message: MessagePDF{
+			Type: mediaAPIType(m.Kind),
+			Body: m.Caption,
+			Media: Media{
+				Mimetype: m.Mimetype,
+				Data:     base64.StdEncoding.EncodeToString(data),
+				Filename: m.Filename,
+			},
+		},
+	})
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	respBody, status, err := c.do(ctx, http.MethodPost, "/send", body)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	if status != http.StatusOK {
+		return MessageResponse{}, parseAPIError(status, respBody)
+	}
+
+	var message MessageResponse
+	if err := json.Unmarshal(respBody, &message); err != nil {
+		return MessageResponse{}, err
+	}
+
+	return message, nil
+}
+
+func (c *Client) sendMediaMultipart(ctx context.Context, recipient string, m MediaUpload, r io.Reader) (MessageResponse, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+
+	go func() {
+		err := writeMultipartMedia(mw, c.WhatsappID, recipient, m, r)
+		mw.Close()
+		pw.CloseWithError(err)
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+"/send/media", pr)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Close = true
+
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return MessageResponse{}, err
+		}
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return MessageResponse{}, parseAPIError(resp.StatusCode, respBody)
+	}
+
+	var message MessageResponse
+	if err := json.Unmarshal(respBody, &message); err != nil {
+		return MessageResponse{}, err
+	}
+
+	return message, nil
+}
+
+func writeMultipartMedia(mw *multipart.Writer, whatsappID, recipient string, m MediaUpload, r io.Reader) error {
+	if err := mw.WriteField("WhatsappID", whatsappID); err != nil {
+		return err
+	}
+	if err := mw.WriteField("recipient", recipient); err != nil {
+		return err
+	}
+	if err := mw.WriteField("type", mediaAPIType(m.Kind)); err != nil {
+		return err
+	}
+	if m.Caption != "" {
+		if err := mw.WriteField("body", m.Caption); err != nil {
+			return err
+		}
+	}
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="media"; filename=%q`, m.Filename))
+	if m.Mimetype != "" {
+		header.Set("Content-Type", m.Mimetype)
+	}
+
+	part, err := mw.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, r)
+	return err
+}
+
+// DownloadMedia fetches the media attached to msg by its MediaKey and
+// decrypts it with WhatsApp's media crypto scheme. Decryption is mandatory:
+// a bad media key, a MAC mismatch, or a length mismatch is returned as an
+// error rather than handing back unverified ciphertext.
+func (c *Client) DownloadMedia(ctx context.Context, msg MessageResponse) (io.ReadCloser, error) {
+	if msg.Result.MediaKey == "" {
+		return nil, errors.New("whatsgate: message has no media")
+	}
+
+	respBody, status, err := c.do(ctx, http.MethodGet, "/media/"+url.PathEscape(msg.Result.MediaKey), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if status != http.StatusOK {
+		return nil, parseAPIError(status, respBody)
+	}
+
+	rawKey, err := base64.StdEncoding.DecodeString(msg.Result.MediaKey)
+	if err != nil {
+		return nil, fmt.Errorf("whatsgate: invalid media key: %w", err)
+	}
+	if len(rawKey) == 0 {
+		return nil, errors.New("whatsgate: empty media key")
+	}
+
+	appInfo := mediacrypto.AppInfoForKind(msg.Result.Type)
+	plaintext, err := mediacrypto.Decrypt(respBody, rawKey, appInfo, msg.Result.FileLength)
+	if err != nil {
+		return nil, err
+	}
+
+	return io.NopCloser(bytes.NewReader(plaintext)), nil
+}
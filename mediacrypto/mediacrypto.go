@@ -0,0 +1,153 @@
+// Package mediacrypto implements the media encryption scheme WhatsApp uses
+// for media attachments: a 32-byte media key is HKDF-SHA256-expanded into an
+// IV, a cipher key, a MAC key, and a ref key, the ciphertext is authenticated
+// with a trailing HMAC-SHA256, and the plaintext is recovered with
+// AES-256-CBC. It has no dependency on the rest of the whatsgate package so
+// callers can verify or decrypt media blobs obtained out-of-band.
+package mediacrypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+)
+
+// App-info strings WhatsApp mixes into the HKDF expansion, one per media kind.
+const (
+	AppInfoImage    = "WhatsApp Image Keys"
+	AppInfoVideo    = "WhatsApp Video Keys"
+	AppInfoAudio    = "WhatsApp Audio Keys"
+	AppInfoDocument = "WhatsApp Document Keys"
+	AppInfoSticker  = "WhatsApp Image Keys"
+)
+
+// expandedKeyLength is iv(16) + cipherKey(32) + macKey(32) + refKey(32).
+const expandedKeyLength = 112
+
+// macSize is the trailing HMAC-SHA256 truncated to 10 bytes that WhatsApp
+// appends to every encrypted media blob.
+const macSize = 10
+
+var (
+	// ErrCiphertextTooShort is returned when a blob is too small to contain
+	// a trailing MAC.
+	ErrCiphertextTooShort = errors.New("mediacrypto: ciphertext too short to contain a MAC")
+	// ErrMACMismatch is returned when the trailing MAC does not match the
+	// computed one, meaning the blob is corrupt or the key is wrong.
+	ErrMACMismatch = errors.New("mediacrypto: MAC verification failed")
+	// ErrLengthMismatch is returned when a caller-supplied expected length
+	// does not match the decrypted plaintext length.
+	ErrLengthMismatch = errors.New("mediacrypto: decrypted length does not match expected file length")
+)
+
+// AppInfoForKind maps a WhatsGate media type ("image", "video", "audio",
+// "document", "sticker") to the app-info string WhatsApp mixes into the key
+// expansion for that kind. Unknown kinds fall back to AppInfoDocument.
+func AppInfoForKind(kind string) string {
+	switch kind {
+	case "image":
+		return AppInfoImage
+	case "video":
+		return AppInfoVideo
+	case "audio":
+		return AppInfoAudio
+	case "sticker":
+		return AppInfoSticker
+	default:
+		return AppInfoDocument
+	}
+}
+
+// ExpandMediaKey HKDF-expands a raw media key into the iv, cipherKey, macKey
+// and refKey used to decrypt and authenticate a media blob of the kind
+// described by appInfo (see AppInfoForKind).
+func ExpandMediaKey(mediaKey []byte, appInfo string) (iv, cipherKey, macKey, refKey []byte) {
+	expanded := hkdfExpand(hkdfExtract(nil, mediaKey), []byte(appInfo), expandedKeyLength)
+	return expanded[0:16], expanded[16:48], expanded[48:80], expanded[80:112]
+}
+
+// Decrypt verifies and decrypts an encrypted media blob (iv-less
+// ciphertext||10-byte HMAC, as returned by the API) under mediaKey. appInfo
+// selects the media kind (see AppInfoForKind). If expectedLength is > 0, the
+// decrypted plaintext length is validated against it.
+func Decrypt(blob, mediaKey []byte, appInfo string, expectedLength int) ([]byte, error) {
+	if len(blob) <= macSize {
+		return nil, ErrCiphertextTooShort
+	}
+
+	iv, cipherKey, macKey, _ := ExpandMediaKey(mediaKey, appInfo)
+
+	ciphertext := blob[:len(blob)-macSize]
+	gotMAC := blob[len(blob)-macSize:]
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+	wantMAC := mac.Sum(nil)[:macSize]
+
+	if !hmac.Equal(gotMAC, wantMAC) {
+		return nil, ErrMACMismatch
+	}
+
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errors.New("mediacrypto: ciphertext is not a multiple of the AES block size")
+	}
+
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(plaintext, ciphertext)
+	plaintext = pkcs7Unpad(plaintext)
+
+	if expectedLength > 0 && len(plaintext) != expectedLength {
+		return nil, ErrLengthMismatch
+	}
+
+	return plaintext, nil
+}
+
+// hkdfExtract and hkdfExpand implement RFC 5869 HKDF-SHA256. They are
+// hand-rolled rather than imported from golang.org/x/crypto/hkdf to keep this
+// package free of external dependencies.
+func hkdfExtract(salt, ikm []byte) []byte {
+	if salt == nil {
+		salt = make([]byte, sha256.Size)
+	}
+	mac := hmac.New(sha256.New, salt)
+	mac.Write(ikm)
+	return mac.Sum(nil)
+}
+
+func hkdfExpand(prk, info []byte, length int) []byte {
+	var (
+		okm  []byte
+		prev []byte
+	)
+
+	for i := byte(1); len(okm) < length; i++ {
+		mac := hmac.New(sha256.New, prk)
+		mac.Write(prev)
+		mac.Write(info)
+		mac.Write([]byte{i})
+		prev = mac.Sum(nil)
+		okm = append(okm, prev...)
+	}
+
+	return okm[:length]
+}
+
+func pkcs7Unpad(b []byte) []byte {
+	if len(b) == 0 {
+		return b
+	}
+	padLen := int(b[len(b)-1])
+	if padLen == 0 || padLen > len(b) {
+		return b
+	}
+	return b[:len(b)-padLen]
+}
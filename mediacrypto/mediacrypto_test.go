@@ -0,0 +1,143 @@
+package mediacrypto
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+)
+
+// RFC 5869 Appendix A test vectors for HKDF-SHA256, verifying the
+// hand-rolled extract/expand against the spec before trusting them with
+// real media keys.
+func TestHKDFRFC5869(t *testing.T) {
+	decode := func(s string) []byte {
+		b, err := hex.DecodeString(s)
+		if err != nil {
+			t.Fatalf("bad test vector: %v", err)
+		}
+		return b
+	}
+
+	cases := []struct {
+		name    string
+		ikm     []byte
+		salt    []byte
+		info    []byte
+		length  int
+		wantPRK []byte
+		wantOKM []byte
+	}{
+		{
+			// Test Case 1: basic case.
+			name:    "case1",
+			ikm:     decode("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+			salt:    decode("000102030405060708090a0b0c"),
+			info:    decode("f0f1f2f3f4f5f6f7f8f9"),
+			length:  42,
+			wantPRK: decode("077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5"),
+			wantOKM: decode("3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865"),
+		},
+		{
+			// Test Case 3: zero-length salt/info, matching how
+			// ExpandMediaKey calls hkdfExtract with a nil salt.
+			name:    "case3_zero_length_salt",
+			ikm:     decode("0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b0b"),
+			salt:    nil,
+			info:    nil,
+			length:  42,
+			wantPRK: decode("19ef24a32c717b167f33a91d6f648bdf96596776afdb6377ac434c1c293ccb04"),
+			wantOKM: decode("8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			prk := hkdfExtract(tc.salt, tc.ikm)
+			if !bytes.Equal(prk, tc.wantPRK) {
+				t.Fatalf("PRK = %x, want %x", prk, tc.wantPRK)
+			}
+
+			okm := hkdfExpand(prk, tc.info, tc.length)
+			if !bytes.Equal(okm, tc.wantOKM) {
+				t.Fatalf("OKM = %x, want %x", okm, tc.wantOKM)
+			}
+		})
+	}
+}
+
+// encryptForTest mirrors what a WhatsApp-compatible sender does: expand the
+// media key, AES-256-CBC-encrypt the (padded) plaintext, and append the
+// truncated HMAC-SHA256 of iv||ciphertext. It exists only to produce fixtures
+// for the TestDecrypt* tests and is the inverse of Decrypt.
+func encryptForTest(t *testing.T, plaintext, mediaKey []byte, appInfo string) []byte {
+	t.Helper()
+
+	iv, cipherKey, macKey, _ := ExpandMediaKey(mediaKey, appInfo)
+
+	padLen := aes.BlockSize - len(plaintext)%aes.BlockSize
+	padded := append(append([]byte{}, plaintext...), bytes.Repeat([]byte{byte(padLen)}, padLen)...)
+
+	block, err := aes.NewCipher(cipherKey)
+	if err != nil {
+		t.Fatalf("aes.NewCipher: %v", err)
+	}
+	ciphertext := make([]byte, len(padded))
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(ciphertext, padded)
+
+	mac := hmac.New(sha256.New, macKey)
+	mac.Write(iv)
+	mac.Write(ciphertext)
+
+	return append(ciphertext, mac.Sum(nil)[:macSize]...)
+}
+
+func TestDecryptRoundTrip(t *testing.T) {
+	mediaKey := make([]byte, 32)
+	if _, err := rand.Read(mediaKey); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	plaintext := []byte("a not-quite-block-aligned WhatsApp media payload!")
+	blob := encryptForTest(t, plaintext, mediaKey, AppInfoImage)
+
+	got, err := Decrypt(blob, mediaKey, AppInfoImage, len(plaintext))
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if !bytes.Equal(got, plaintext) {
+		t.Fatalf("Decrypt = %q, want %q", got, plaintext)
+	}
+}
+
+func TestDecryptRejectsTamperedCiphertext(t *testing.T) {
+	mediaKey := make([]byte, 32)
+	if _, err := rand.Read(mediaKey); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	blob := encryptForTest(t, []byte("hello"), mediaKey, AppInfoDocument)
+	blob[0] ^= 0xff // flip a ciphertext byte without touching the trailing MAC
+
+	if _, err := Decrypt(blob, mediaKey, AppInfoDocument, 0); err != ErrMACMismatch {
+		t.Fatalf("Decrypt error = %v, want %v", err, ErrMACMismatch)
+	}
+}
+
+func TestDecryptRejectsLengthMismatch(t *testing.T) {
+	mediaKey := make([]byte, 32)
+	if _, err := rand.Read(mediaKey); err != nil {
+		t.Fatalf("rand.Read: %v", err)
+	}
+
+	plaintext := []byte("hello")
+	blob := encryptForTest(t, plaintext, mediaKey, AppInfoVideo)
+
+	if _, err := Decrypt(blob, mediaKey, AppInfoVideo, len(plaintext)+1); err != ErrLengthMismatch {
+		t.Fatalf("Decrypt error = %v, want %v", err, ErrLengthMismatch)
+	}
+}
@@ -0,0 +1,180 @@
+package whatsgate
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// Message is implemented by every payload that can be sent via SendTyped.
+// marshalMessage produces the JSON shape the API expects for the "message"
+// field, including its own "type" discriminator.
+type Message interface {
+	marshalMessage() ([]byte, error)
+}
+
+// TextMessage is a plain text message.
+type TextMessage struct {
+	Body string
+}
+
+func (m TextMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		Body string `json:"body"`
+	}{"text", m.Body})
+}
+
+// ImageMessage sends an image, optionally captioned, from Media.
+type ImageMessage struct {
+	Body  string
+	Media Media
+}
+
+func (m ImageMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Body  string `json:"body"`
+		Media Media  `json:"media"`
+	}{"image", m.Body, m.Media})
+}
+
+// DocumentMessage sends a document attachment, optionally captioned, from Media.
+type DocumentMessage struct {
+	Body  string
+	Media Media
+}
+
+func (m DocumentMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		Body  string `json:"body"`
+		Media Media  `json:"media"`
+	}{"doc", m.Body, m.Media})
+}
+
+// LocationMessage shares a geographic location.
+type LocationMessage struct {
+	Lat     float64
+	Lng     float64
+	Name    string
+	Address string
+}
+
+func (m LocationMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string  `json:"type"`
+		Lat     float64 `json:"lat"`
+		Lng     float64 `json:"lng"`
+		Name    string  `json:"name,omitempty"`
+		Address string  `json:"address,omitempty"`
+	}{"location", m.Lat, m.Lng, m.Name, m.Address})
+}
+
+// ContactMessage shares a contact card as a vCard.
+type ContactMessage struct {
+	VCard string
+}
+
+func (m ContactMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type  string `json:"type"`
+		VCard string `json:"vcard"`
+	}{"contact", m.VCard})
+}
+
+// Button is a single quick-reply option in a ButtonsMessage.
+type Button struct {
+	ID   string `json:"id"`
+	Text string `json:"text"`
+}
+
+// ButtonsMessage presents the recipient with up to a handful of quick-reply buttons.
+type ButtonsMessage struct {
+	Body    string
+	Footer  string
+	Buttons []Button
+}
+
+func (m ButtonsMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type    string   `json:"type"`
+		Body    string   `json:"body"`
+		Footer  string   `json:"footer,omitempty"`
+		Buttons []Button `json:"buttons"`
+	}{"buttons", m.Body, m.Footer, m.Buttons})
+}
+
+// Row is a single selectable entry in a ListMessage Section.
+type Row struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// Section groups Rows under a heading within a ListMessage.
+type Section struct {
+	Title string `json:"title"`
+	Rows  []Row  `json:"rows"`
+}
+
+// ListMessage presents the recipient with a menu of selectable rows grouped into sections.
+type ListMessage struct {
+	Body       string
+	ButtonText string
+	Sections   []Section
+}
+
+func (m ListMessage) marshalMessage() ([]byte, error) {
+	return json.Marshal(struct {
+		Type       string    `json:"type"`
+		Body       string    `json:"body"`
+		ButtonText string    `json:"buttonText"`
+		Sections   []Section `json:"sections"`
+	}{"list", m.Body, m.ButtonText, m.Sections})
+}
+
+// SendOption configures an individual SendTyped call.
+type SendOption func(*MessageRequest)
+
+// WithReplyTo quotes a prior message by its MessageResponse.Result.Id.
+func WithReplyTo(messageID string) SendOption {
+	return func(r *MessageRequest) {
+		r.ReplyTo = messageID
+	}
+}
+
+// SendTyped sends any Message to recipient, the generic entry point behind
+// SendMessageCtx, SendPDFCtx and friends.
+func (c *Client) SendTyped(ctx context.Context, recipient string, m Message, opts ...SendOption) (MessageResponse, error) {
+	req := MessageRequest{
+		WhatsappID: c.WhatsappID,
+		Async:      false,
+		Recipient:  Recipient{Number: recipient},
+		Message:    m,
+	}
+	for _, opt := range opts {
+		opt(&req)
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	respBody, status, err := c.do(ctx, http.MethodPost, "/send", body)
+	if err != nil {
+		return MessageResponse{}, err
+	}
+
+	if status != http.StatusOK {
+		return MessageResponse{}, parseAPIError(status, respBody)
+	}
+
+	var message MessageResponse
+	if err := json.Unmarshal(respBody, &message); err != nil {
+		return MessageResponse{}, err
+	}
+
+	return message, nil
+}
@@ -0,0 +1,61 @@
+package whatsgate
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// tokenBucket is a minimal token-bucket rate limiter used to cap the
+// number of requests per second a Client issues. It has no external
+// dependencies so the package stays free of a third-party rate limiter.
+type tokenBucket struct {
+	mu     sync.Mutex
+	tokens float64
+	max    float64
+	rate   float64 // tokens added per second
+	last   time.Time
+}
+
+func newTokenBucket(rps, burst int) *tokenBucket {
+	if burst <= 0 {
+		burst = rps
+	}
+
+	return &tokenBucket{
+		tokens: float64(burst),
+		max:    float64(burst),
+		rate:   float64(rps),
+		last:   time.Now(),
+	}
+}
+
+// Wait blocks until a token is available or ctx is done.
+func (b *tokenBucket) Wait(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		now := time.Now()
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.max {
+			b.tokens = b.max
+		}
+		b.last = now
+
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return nil
+		}
+
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		b.mu.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
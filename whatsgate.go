@@ -2,35 +2,266 @@ package whatsgate
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
-	"errors"
 	"io"
 	"log/slog"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 type transport struct {
-	rt      http.RoundTripper
-	xApiKey string
+	rt        http.RoundTripper
+	xApiKey   string
+	userAgent string
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
 	req.Header.Add("X-Api-Key", t.xApiKey)
 	req.Header.Add("Content-type", "application/json")
+	if t.userAgent != "" {
+		req.Header.Set("User-Agent", t.userAgent)
+	}
 	return t.rt.RoundTrip(req)
 }
 
+type retryConfig struct {
+	maxAttempts int
+	backoff     time.Duration
+}
+
 type Client struct {
 	httpClient *http.Client
 	url        string
 	WhatsappID string
+	logger     *slog.Logger
+	retry      retryConfig
+	limiter    *tokenBucket
+	webhooks   chan Event
+}
+
+// clientConfig collects everything a ClientOption can tweak before the
+// Client is built. It exists so options can be applied in any order
+// without the Client itself ever being in a half-configured state.
+type clientConfig struct {
+	httpClient  *http.Client
+	baseURL     string
+	timeout     time.Duration
+	logger      *slog.Logger
+	userAgent   string
+	maxAttempts int
+	backoff     time.Duration
+	rps         int
+	burst       int
+}
+
+// ClientOption configures a Client constructed by NewClient.
+type ClientOption func(*clientConfig)
+
+// WithHTTPClient overrides the underlying *http.Client used for requests.
+// NewClient still wraps its Transport to attach the API key header.
+func WithHTTPClient(httpClient *http.Client) ClientOption {
+	return func(c *clientConfig) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithBaseURL overrides the API base URL, e.g. for testing against a mock server.
+func WithBaseURL(baseURL string) ClientOption {
+	return func(c *clientConfig) {
+		c.baseURL = baseURL
+	}
+}
+
+// WithTimeout sets the underlying http.Client's Timeout.
+func WithTimeout(d time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.timeout = d
+	}
+}
+
+// WithLogger sets the logger used for request/retry diagnostics.
+// The default client logs to slog.Default().
+func WithLogger(logger *slog.Logger) ClientOption {
+	return func(c *clientConfig) {
+		c.logger = logger
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) ClientOption {
+	return func(c *clientConfig) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithRetry enables automatic retries for failed or throttled requests.
+// maxAttempts is the total number of attempts (1 means no retry). backoff
+// is the base delay used for exponential backoff with jitter; a 429 or 5xx
+// response carrying a Retry-After header takes precedence over it.
+func WithRetry(maxAttempts int, backoff time.Duration) ClientOption {
+	return func(c *clientConfig) {
+		c.maxAttempts = maxAttempts
+		c.backoff = backoff
+	}
+}
+
+// WithRateLimit caps outgoing requests to rps per second, allowing short
+// bursts of up to burst requests. It wraps every request the Client makes,
+// including retries.
+func WithRateLimit(rps, burst int) ClientOption {
+	return func(c *clientConfig) {
+		c.rps = rps
+		c.burst = burst
+	}
+}
+
+// NewClient builds a Client for the given API key and WhatsApp instance ID.
+// By default it has no retry and no rate limit; pass ClientOptions to enable them.
+func NewClient(apiKey, whatsappID string, opts ...ClientOption) *Client {
+	cfg := clientConfig{
+		httpClient:  &http.Client{},
+		baseURL:     "https://whatsgate.ru/api/v1",
+		logger:      slog.Default(),
+		maxAttempts: 1,
+	}
+
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if cfg.timeout > 0 {
+		cfg.httpClient.Timeout = cfg.timeout
+	}
+
+	rt := cfg.httpClient.Transport
+	if rt == nil {
+		rt = http.DefaultTransport
+	}
+	cfg.httpClient.Transport = &transport{rt: rt, xApiKey: apiKey, userAgent: cfg.userAgent}
+
+	var limiter *tokenBucket
+	if cfg.rps > 0 {
+		limiter = newTokenBucket(cfg.rps, cfg.burst)
+	}
+
+	return &Client{
+		httpClient: cfg.httpClient,
+		url:        cfg.baseURL,
+		WhatsappID: whatsappID,
+		logger:     cfg.logger,
+		retry:      retryConfig{maxAttempts: cfg.maxAttempts, backoff: cfg.backoff},
+		limiter:    limiter,
+		webhooks:   make(chan Event, 64),
+	}
+}
+
+// do sends body to path via method and returns the response body and
+// status code, applying the Client's rate limit and retry policy around
+// the request. body may be nil for methods that carry no payload.
+func (c *Client) do(ctx context.Context, method, path string, body []byte) ([]byte, int, error) {
+	attempts := c.retry.maxAttempts
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	var lastErr error
+
+	for attempt := 0; attempt < attempts; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return nil, 0, err
+			}
+		}
+
+		var bodyReader io.Reader
+		if body != nil {
+			bodyReader = bytes.NewReader(body)
+		}
+
+		r, err := http.NewRequestWithContext(ctx, method, c.url+path, bodyReader)
+		if err != nil {
+			return nil, 0, err
+		}
+		r.Close = true
+
+		resp, err := c.httpClient.Do(r)
+		if err != nil {
+			lastErr = err
+			c.logger.Error("whatsgate: request failed", "path", path, "attempt", attempt+1, "error", err)
+			if attempt == attempts-1 {
+				break
+			}
+			if sleepErr := c.sleep(ctx, c.backoffDuration(attempt, 0)); sleepErr != nil {
+				return nil, 0, sleepErr
+			}
+			continue
+		}
+
+		respBody, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, resp.StatusCode, err
+		}
+
+		if (&APIError{StatusCode: resp.StatusCode}).Retryable() && attempt < attempts-1 {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			c.logger.Warn("whatsgate: retrying request", "path", path, "status", resp.StatusCode, "attempt", attempt+1)
+			if sleepErr := c.sleep(ctx, c.backoffDuration(attempt, retryAfter)); sleepErr != nil {
+				return nil, resp.StatusCode, sleepErr
+			}
+			continue
+		}
+
+		return respBody, resp.StatusCode, nil
+	}
+
+	return nil, 0, lastErr
+}
+
+func (c *Client) backoffDuration(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	if c.retry.backoff <= 0 {
+		return 0
+	}
+
+	d := c.retry.backoff * time.Duration(1<<attempt)
+	jitter := time.Duration(rand.Int63n(int64(d)/2 + 1))
+	return d + jitter
 }
 
-func NewClient(apiKey, whatsappID string) *Client {
-	httpClient := &http.Client{Transport: &transport{rt: http.DefaultTransport, xApiKey: apiKey}}
+func (c *Client) sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
 
-	return &Client{httpClient: httpClient, url: "https://whatsgate.ru/api/v1", WhatsappID: whatsappID}
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return time.Until(t)
+	}
+	return 0
 }
 
 type MessageResponse struct {
@@ -40,6 +271,7 @@ type MessageResponse struct {
 		Ack         int    `json:"ack"`
 		HasMedia    bool   `json:"hasMedia"`
 		MediaKey    string `json:"mediaKey"`
+		FileLength  int    `json:"fileLength"`
 		Body        string `json:"body"`
 		Type        string `json:"type"`
 		Timestamp   int    `json:"timestamp"`
@@ -50,11 +282,33 @@ type MessageResponse struct {
 	} `json:"result"`
 }
 
+// MessageRequest is the envelope every send call wraps its Message in.
+// ReplyTo, when set, quotes a prior MessageResponse.Result.Id.
 type MessageRequest struct {
 	WhatsappID string    `json:"WhatsappID"`
 	Async      bool      `json:"async"`
 	Recipient  Recipient `json:"recipient"`
-	Message    Message   `json:"message"`
+	Message    Message   `json:"-"`
+	ReplyTo    string    `json:"replyTo,omitempty"`
+}
+
+// MarshalJSON serializes r, delegating the polymorphic Message field to its
+// own marshalMessage implementation.
+func (r MessageRequest) MarshalJSON() ([]byte, error) {
+	var msgJSON json.RawMessage
+	if r.Message != nil {
+		raw, err := r.Message.marshalMessage()
+		if err != nil {
+			return nil, err
+		}
+		msgJSON = raw
+	}
+
+	type alias MessageRequest
+	return json.Marshal(struct {
+		alias
+		Message json.RawMessage `json:"message"`
+	}{alias(r), msgJSON})
 }
 
 type CheckRequest struct {
@@ -71,11 +325,6 @@ type Recipient struct {
 	Number string `json:"number"`
 }
 
-type Message struct {
-	Type string `json:"type"`
-	Body string `json:"body"`
-}
-
 type MessagePDF struct {
 	Type  string `json:"type"`
 	Body  string `json:"body"`
@@ -95,110 +344,51 @@ type MessagePDFRequest struct {
 	Message    MessagePDF `json:"message"`
 }
 
+// SendMessage sends a text message. It is equivalent to calling
+// SendMessageCtx with context.Background().
 func (c *Client) SendMessage(recipientPhone, text string) (MessageResponse, error) {
-	body, err := json.Marshal(MessageRequest{
-		WhatsappID: c.WhatsappID,
-		Async:      false,
-		Recipient:  Recipient{Number: recipientPhone},
-		Message:    Message{Type: "text", Body: text},
-	})
-	if err != nil {
-		return MessageResponse{}, err
-	}
-
-	r, err := http.NewRequest("POST", c.url+"/send", bytes.NewBuffer(body))
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-	r.Close = true
-
-	req, err := c.httpClient.Do(r)
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-
-	defer req.Body.Close()
-
-	respBody, err := io.ReadAll(req.Body)
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-
-	if req.StatusCode != http.StatusOK {
-		return MessageResponse{}, errors.New("некорректный номер WhatsApp")
-	}
-
-	var message MessageResponse
-
-	err = json.Unmarshal(respBody, &message)
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
+	return c.SendMessageCtx(context.Background(), recipientPhone, text)
+}
 
-	return message, nil
+// SendMessageCtx sends a text message, honoring ctx cancellation across
+// the request and any configured retries.
+func (c *Client) SendMessageCtx(ctx context.Context, recipientPhone, text string) (MessageResponse, error) {
+	return c.SendTyped(ctx, recipientPhone, TextMessage{Body: text})
 }
 
+// SendPDF sends a PDF document. It is equivalent to calling SendPDFCtx
+// with context.Background().
 func (c *Client) SendPDF(recipientPhone, text, filename string, pdf io.Reader) (MessageResponse, error) {
+	return c.SendPDFCtx(context.Background(), recipientPhone, text, filename, pdf)
+}
+
+// SendPDFCtx sends a PDF document, honoring ctx cancellation across the
+// request and any configured retries.
+func (c *Client) SendPDFCtx(ctx context.Context, recipientPhone, text, filename string, pdf io.Reader) (MessageResponse, error) {
 	b, err := io.ReadAll(pdf)
 	if err != nil {
 		return MessageResponse{}, err
 	}
 
-	body, err := json.Marshal(MessagePDFRequest{
-		WhatsappID: c.WhatsappID,
-		Async:      false,
-		Recipient:  Recipient{Number: recipientPhone},
-		Message: MessagePDF{Type: "doc", Body: text, Media: Media{
+	return c.SendTyped(ctx, recipientPhone, DocumentMessage{
+		Body: text,
+		Media: Media{
 			Mimetype: "application/pdf",
 			Data:     base64.StdEncoding.EncodeToString(b),
 			Filename: filename,
-		}},
+		},
 	})
-	if err != nil {
-		return MessageResponse{}, err
-	}
-
-	r, err := http.NewRequest("POST", c.url+"/send", bytes.NewBuffer(body))
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-	r.Close = true
-
-	req, err := c.httpClient.Do(r)
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-
-	defer req.Body.Close()
-
-	respBody, err := io.ReadAll(req.Body)
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-
-	if req.StatusCode != http.StatusOK {
-		return MessageResponse{}, errors.New(req.Status)
-	}
-
-	var message MessageResponse
-
-	err = json.Unmarshal(respBody, &message)
-	if err != nil {
-		slog.Error(err.Error())
-		return MessageResponse{}, err
-	}
-
-	return message, nil
 }
 
+// Check reports whether phone is registered on WhatsApp. It is equivalent
+// to calling CheckCtx with context.Background().
 func (c *Client) Check(phone string) (bool, error) {
+	return c.CheckCtx(context.Background(), phone)
+}
+
+// CheckCtx reports whether phone is registered on WhatsApp, honoring ctx
+// cancellation across the request and any configured retries.
+func (c *Client) CheckCtx(ctx context.Context, phone string) (bool, error) {
 	body, err := json.Marshal(CheckRequest{
 		WhatsappID: c.WhatsappID,
 		Number:     phone,
@@ -207,29 +397,18 @@ func (c *Client) Check(phone string) (bool, error) {
 		return false, err
 	}
 
-	r, err := http.NewRequest("POST", c.url+"/check", bytes.NewBuffer(body))
+	respBody, status, err := c.do(ctx, http.MethodPost, "/check", body)
 	if err != nil {
 		return false, err
 	}
-	r.Close = true
 
-	req, err := c.httpClient.Do(r)
-	if err != nil {
-		slog.Error(err.Error())
-		return false, err
-	}
-
-	if req.StatusCode != http.StatusOK {
-		return false, errors.New(req.Status)
+	if status != http.StatusOK {
+		return false, parseAPIError(status, respBody)
 	}
 
-	defer req.Body.Close()
-
 	var response CheckResponse
-
-	err = json.NewDecoder(req.Body).Decode(&response)
-	if err != nil {
-		slog.Error(err.Error())
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		c.logger.Error(err.Error())
 		return false, err
 	}
 